@@ -1,106 +1,263 @@
 package main
 
 import (
+    "bytes"
     "context"
+    "crypto/tls"
     "encoding/json"
     "fmt"
+    "io/ioutil"
     "net/http"
     "os"
+    "os/signal"
     "runtime"
-    "time"
-    "bytes"
-    "io/ioutil"
     "strings"
+    "syscall"
+    "time"
+
+    "github.com/younes-barhouni/cdots/agent/internal/admin"
+    "github.com/younes-barhouni/cdots/agent/internal/collector"
+    "github.com/younes-barhouni/cdots/agent/internal/config"
+    "github.com/younes-barhouni/cdots/agent/internal/httpx"
+    "github.com/younes-barhouni/cdots/agent/internal/logging"
+    "github.com/younes-barhouni/cdots/agent/internal/patcher"
+    "github.com/younes-barhouni/cdots/agent/internal/status"
+    "github.com/younes-barhouni/cdots/agent/internal/supervisor"
 )
 
+// collectorTimeout bounds how long any single collector may take on a
+// tick before its sample is dropped.
+const collectorTimeout = 10 * time.Second
+
 // buildVersion will be set during build time using -ldflags.
 var buildVersion = "development"
 
-// interval defines how often metrics should be collected.  In a full
-// implementation this would be configurable via a config file or environment
-// variable.  For now it is hard‑coded to 30 seconds.
-const interval = 30 * time.Second
+// main is the entry point for the agent.  It loads configuration, wires
+// up the collection and patch services, and hands them to a supervisor,
+// which keeps them running until an OS signal asks the agent to shut
+// down.
+func main() {
+    cfg, err := config.Load(os.Args[1:])
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "cdot-RMM agent: %v\n", err)
+        os.Exit(1)
+    }
 
-// patchInterval defines how often the agent checks for scheduled patches.
-// This can be overridden via the PATCH_INTERVAL environment variable (in
-// minutes).  The default is 60 minutes.
-var patchInterval = 60 * time.Minute
+    logger := logging.New("agent", cfg.LogLevel, cfg.LogFormat)
+    watchLogLevel(logger, os.Args[1:])
 
-// deviceID holds the unique identifier assigned by the device service
-// upon registration.  It is populated by registerDevice and used
-// throughout the agent to fetch patch assignments and report status.
-var deviceID string
+    logger.Info("cdot-RMM agent starting", "version", buildVersion)
 
-// main is the entry point for the agent.  It initialises any required
-// collectors and enters a loop where it gathers metrics and reports them
-// to the configured backend.
-func main() {
-    fmt.Printf("cdot‑RMM agent starting (version %s)\n", buildVersion)
+    // ctx is cancelled on SIGINT/SIGTERM, which the supervisor propagates
+    // into every service so they can shut down cleanly.
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    tlsConfig, err := cfg.TLS.TLSConfig()
+    if err != nil {
+        logger.Error("invalid TLS configuration", "error", err)
+        os.Exit(1)
+    }
+
+    // httpClient is shared by every service that talks to the backend so
+    // retries, backoff and the attempt/failure counters are consistent
+    // across registration, metrics and patch reporting.
+    httpClient := httpx.New(httpx.Config{
+        MaxAttempts:       cfg.Retry.MaxAttempts,
+        Backoff:           cfg.Retry.Backoff(),
+        RetryBudget:       cfg.Retry.Budget,
+        RetryBudgetWindow: time.Duration(cfg.Retry.BudgetWindow),
+        TLSClientConfig:   tlsConfig,
+    })
+
+    // tracker holds state (device identity, collector ticks, last patch
+    // check) that the admin endpoint reports on; adminMetrics holds the
+    // agent's own Prometheus counters. Both are shared across services.
+    tracker := status.NewTracker()
+    adminMetrics := admin.NewMetrics()
+
+    metricsLogger := logger.Named("metrics")
+    registry, err := buildMetricsRegistry(cfg, httpClient, tlsConfig, tracker, adminMetrics, metricsLogger)
+    if err != nil {
+        metricsLogger.Error("failed to build metrics sink", "error", err)
+        os.Exit(1)
+    }
+
+    patchLogger := logger.Named("patch")
+    patchBackend, err := patcher.Select(cfg.PatchDryRun)
+    if err != nil {
+        patchLogger.Error("failed to select patch backend", "error", err)
+        os.Exit(1)
+    }
+    patchLogger.Info("selected patch backend", "backend", patchBackend.Name())
+
+    sup := supervisor.New()
+    sup.OnRestart(func(name string, err error, attempt int, delay time.Duration) {
+        logger.Warn("service failed, restarting", "service", name, "attempt", attempt, "error", err, "delay", delay)
+    })
+    sup.Add("registration", &registrationService{client: httpClient, cfg: cfg, tracker: tracker, metrics: adminMetrics, logger: logger.Named("registration")})
+    sup.Add("metrics", &metricsService{interval: time.Duration(cfg.MetricsInterval), registry: registry, logger: metricsLogger})
+    sup.Add("patcher", &patchService{interval: time.Duration(cfg.PatchInterval), client: httpClient, backend: patchBackend, cfg: cfg, tracker: tracker, metrics: adminMetrics, logger: patchLogger})
+    if cfg.AdminEnabled {
+        sup.Add("admin", &admin.Server{
+            Addr:       cfg.AdminAddr,
+            Version:    buildVersion,
+            HTTPClient: httpClient,
+            Metrics:    adminMetrics,
+            Tracker:    tracker,
+            Logger:     logger.Named("admin"),
+        })
+    }
+
+    sup.Serve(ctx)
+    logger.Info("agent shutting down")
+}
 
-    // Attempt to register this device with the central server.  This
-    // registration should complete within one minute of startup to
-    // satisfy the onboarding requirement.  Errors are logged but do
-    // not stop the agent from running.
+// watchLogLevel starts a goroutine that, on SIGHUP, reloads the config
+// file and env/flag overlay from scratch and applies the resulting
+// LogLevel to logger. A process's own environment and flags are fixed
+// at exec time, so re-reading them can never observe a change; the
+// config file is the only one of the three sources an operator can
+// still edit post-startup, which is what lets this actually raise or
+// lower verbosity on a running agent without a restart.
+func watchLogLevel(logger logging.Logger, args []string) {
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
     go func() {
-        id, err := registerDevice()
-        if err != nil {
-            fmt.Printf("device registration failed: %v\n", err)
-        } else {
-            deviceID = id
+        for range hup {
+            cfg, err := config.Load(args)
+            if err != nil {
+                logger.Error("failed to reload config on SIGHUP", "error", err)
+                continue
+            }
+            lvl := logging.LevelFromString(cfg.LogLevel)
+            logger.SetLevel(lvl)
+            logger.Info("log level reloaded", "level", lvl)
         }
     }()
+}
 
-    // Override patch interval from environment if provided
-    if v := os.Getenv("PATCH_INTERVAL_MINUTES"); v != "" {
-        if mins, err := time.ParseDuration(v + "m"); err == nil {
-            patchInterval = mins
-        }
+// registrationService registers this device with the device service once
+// and then blocks until the agent is asked to shut down. If registration
+// fails, it returns the error so the supervisor retries it with backoff.
+type registrationService struct {
+    client  *httpx.Client
+    cfg     config.Config
+    tracker *status.Tracker
+    metrics *admin.Metrics
+    logger  logging.Logger
+}
+
+func (s *registrationService) Serve(ctx context.Context) error {
+    s.metrics.RegistrationAttempts.Inc()
+    id, err := registerDevice(ctx, s.client, s.cfg, s.logger)
+    if err != nil {
+        return err
     }
+    s.tracker.SetDeviceID(id)
+    <-ctx.Done()
+    return nil
+}
 
-    // create a cancellable context so that if we implement signals or
-    // graceful shutdown later we can stop the collection loop cleanly.
-    ctx, cancel := context.WithCancel(context.Background())
-    defer cancel()
+// metricsService ticks every interval and runs the collector registry,
+// stopping once ctx is cancelled.
+type metricsService struct {
+    interval time.Duration
+    registry *collector.Registry
+    logger   logging.Logger
+}
 
-    // In the future we will parse command‑line flags, load a config file and
-    // initialise TLS certificates / JWT tokens here.  For now, just start the
-    // collection loop.
-    ticker := time.NewTicker(interval)
+func (s *metricsService) Serve(ctx context.Context) error {
+    ticker := time.NewTicker(s.interval)
     defer ticker.Stop()
-    // Patch check ticker
-    patchTicker := time.NewTicker(patchInterval)
-    defer patchTicker.Stop()
     for {
         select {
         case <-ticker.C:
-            collectAndSendMetrics()
-        case <-patchTicker.C:
-            // Only perform patch check if deviceID has been set
-            if deviceID != "" {
-                go checkForPatches(deviceID)
+            if err := s.registry.CollectAndSend(ctx); err != nil {
+                s.logger.Error("failed to send metrics", "error", err)
             }
         case <-ctx.Done():
-            fmt.Println("agent shutting down")
-            return
+            return nil
         }
     }
 }
 
-// collectAndSendMetrics performs a single round of metric collection and
-// transmits the result to the backend.  Currently this function only prints
-// placeholder values; in a future commit it will gather real system data
-// using libraries such as gopsutil.
-func collectAndSendMetrics() {
-    // TODO: Replace with real collection logic (CPU, memory, disk, network)
-    timestamp := time.Now().UTC().Format(time.RFC3339)
-    fmt.Printf("[%s] Collecting metrics: CPU=0%%, RAM=0%%, Disk=0%%, Net=0kbps\n", timestamp)
+// buildMetricsRegistry wires up the collectors named in
+// cfg.EnabledCollectors and a Sink selected by cfg.MetricsSink ("http",
+// the default, or "grpc"). Every tick's duration and, on success, its
+// timestamp are recorded into tracker and metrics for the admin
+// endpoint.
+func buildMetricsRegistry(cfg config.Config, httpClient *httpx.Client, tlsConfig *tls.Config, tracker *status.Tracker, metrics *admin.Metrics, logger logging.Logger) (*collector.Registry, error) {
+    sink, err := buildMetricsSink(cfg, httpClient, tlsConfig)
+    if err != nil {
+        return nil, err
+    }
+    registry := collector.New(sink, collectorTimeout)
+    registry.OnError(func(name string, err error) {
+        logger.Warn("collector failed", "collector", name, "error", err)
+    })
+    registry.OnTick(func(name string, duration time.Duration, err error) {
+        metrics.CollectorDuration.WithLabelValues(name).Observe(duration.Seconds())
+        if err == nil {
+            tracker.RecordCollectorTick(name, time.Now())
+        }
+    })
+    available := map[string]collector.Collector{
+        "cpu":     collector.CPUCollector{},
+        "memory":  collector.MemoryCollector{},
+        "disk":    collector.DiskCollector{},
+        "network": collector.NetworkCollector{},
+    }
+    for _, name := range cfg.EnabledCollectors {
+        c, ok := available[strings.ToLower(name)]
+        if !ok {
+            logger.Warn("ignoring unknown collector", "collector", name)
+            continue
+        }
+        registry.Register(c)
+    }
+    return registry, nil
+}
 
-    // TODO: Send metrics to backend via gRPC or HTTP
+func buildMetricsSink(cfg config.Config, httpClient *httpx.Client, tlsConfig *tls.Config) (collector.Sink, error) {
+    if strings.EqualFold(cfg.MetricsSink, "grpc") {
+        return collector.NewGRPCSink(cfg.MetricsServiceAddr, tlsConfig)
+    }
+    return collector.NewHTTPSink(cfg.MetricsServiceURL, httpClient), nil
+}
+
+// patchService ticks every interval and checks for scheduled patches,
+// stopping once ctx is cancelled. It waits for registrationService to
+// populate the device ID (via tracker) before doing any work.
+type patchService struct {
+    interval time.Duration
+    client   *httpx.Client
+    backend  patcher.Backend
+    cfg      config.Config
+    tracker  *status.Tracker
+    metrics  *admin.Metrics
+    logger   logging.Logger
+}
+
+func (s *patchService) Serve(ctx context.Context) error {
+    ticker := time.NewTicker(s.interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+            if id := s.tracker.DeviceID(); id != "" {
+                checkForPatches(ctx, s.client, s.backend, s.cfg, id, s.tracker, s.metrics, s.logger.With("device_id", id))
+            }
+        case <-ctx.Done():
+            return nil
+        }
+    }
 }
 
 // registerDevice sends a registration request to the device service.  It
 // collects basic host information such as hostname and operating system.
-func registerDevice() (string, error) {
+// The request is retried by client on network errors and 5xx responses.
+func registerDevice(ctx context.Context, client *httpx.Client, cfg config.Config, logger logging.Logger) (string, error) {
     hostname, _ := os.Hostname()
     deviceInfo := map[string]interface{}{
         "hostname":      hostname,
@@ -111,17 +268,15 @@ func registerDevice() (string, error) {
     if err != nil {
         return "", fmt.Errorf("failed to marshal device info: %w", err)
     }
-    url := os.Getenv("DEVICE_SERVICE_URL")
-    if url == "" {
-        url = "http://localhost:3001/api/devices/register"
-    }
-    req, err := http.NewRequest("POST", url, bytes.NewReader(body))
-    if err != nil {
-        return "", fmt.Errorf("failed to create request: %w", err)
-    }
-    req.Header.Set("Content-Type", "application/json")
-    client := &http.Client{Timeout: 15 * time.Second}
-    resp, err := client.Do(req)
+
+    resp, err := client.Do(ctx, "device-register", 15*time.Second, func() (*http.Request, error) {
+        req, err := http.NewRequest("POST", cfg.DeviceServiceURL, bytes.NewReader(body))
+        if err != nil {
+            return nil, err
+        }
+        req.Header.Set("Content-Type", "application/json")
+        return req, nil
+    })
     if err != nil {
         return "", fmt.Errorf("failed to post registration: %w", err)
     }
@@ -132,92 +287,147 @@ func registerDevice() (string, error) {
         var respData map[string]interface{}
         if err := json.Unmarshal(bodyBytes, &respData); err == nil {
             if id, ok := respData["device_id"].(string); ok {
-                fmt.Println("device registered successfully with id", id)
+                logger.Info("device registered successfully", "device_id", id)
                 return id, nil
             }
         }
-        fmt.Println("device registered successfully")
+        logger.Info("device registered successfully")
         return "", nil
     }
     return "", fmt.Errorf("unexpected response status: %s", resp.Status)
 }
 
 // checkForPatches queries the patch service for any approved patches
-// scheduled for this device.  It then reports progress as it
-// installs each patch (simulated).  This function runs on a
-// goroutine and should not block the main loop.
-func checkForPatches(id string) {
-    // Determine base URL for patch service
-    baseURL := os.Getenv("PATCH_SERVICE_URL")
-    if baseURL == "" {
-        baseURL = "http://localhost:3004/api/patches"
-    }
-    // Build fetch URL
-    fetchURL := strings.TrimRight(baseURL, "/") + "/" + id
-    client := &http.Client{Timeout: 20 * time.Second}
-    resp, err := client.Get(fetchURL)
+// scheduled for this device, then installs each one (skipping any still
+// outside its maintenance window) via backend and reports the outcome.
+// It runs synchronously on patchService's goroutine, so a long install
+// delays only that service's next check. Its outcome is recorded into tracker for the admin endpoint's /status
+// route.
+func checkForPatches(ctx context.Context, client *httpx.Client, backend patcher.Backend, cfg config.Config, id string, tracker *status.Tracker, metrics *admin.Metrics, logger logging.Logger) {
+    fetchURL := strings.TrimRight(cfg.PatchServiceURL, "/") + "/" + id
+
+    resp, err := client.Do(ctx, "patch-fetch", 20*time.Second, func() (*http.Request, error) {
+        return http.NewRequest("GET", fetchURL, nil)
+    })
     if err != nil {
-        fmt.Printf("patch check failed: %v\n", err)
+        logger.Error("patch check failed", "error", err)
+        tracker.RecordPatchCheck(status.PatchCheck{Time: time.Now(), Error: err.Error()})
         return
     }
     defer resp.Body.Close()
+    installed := 0
     if resp.StatusCode >= 200 && resp.StatusCode < 300 {
         body, _ := ioutil.ReadAll(resp.Body)
         var data struct {
             Assignments []struct {
-                PatchID    int         `json:"patch_id"`
-                ScheduleAt interface{} `json:"schedule_at"`
-                Status     string      `json:"status"`
-                Name       string      `json:"name"`
-                Vendor     string      `json:"vendor"`
-                Severity   string      `json:"severity"`
-                Description string     `json:"description"`
+                PatchID     int         `json:"patch_id"`
+                ScheduleAt  interface{} `json:"schedule_at"`
+                Status      string      `json:"status"`
+                Name        string      `json:"name"`
+                Version     string      `json:"version"`
+                Vendor      string      `json:"vendor"`
+                Severity    string      `json:"severity"`
+                Description string      `json:"description"`
             } `json:"assignments"`
         }
         if err := json.Unmarshal(body, &data); err == nil {
             for _, asg := range data.Assignments {
-                // For each assignment mark as in_progress
-                reportPatchStatus(id, asg.PatchID, "in_progress", "")
-                fmt.Printf("Installing patch %d (%s)\n", asg.PatchID, asg.Name)
-                // Simulate download and install
-                time.Sleep(5 * time.Second)
-                // After install mark as success
-                reportPatchStatus(id, asg.PatchID, "success", "")
-                fmt.Printf("Patch %d installed successfully\n", asg.PatchID)
+                patchLogger := logger.With("patch_id", asg.PatchID)
+                scheduledAt, hasSchedule := parseScheduleAt(asg.ScheduleAt)
+                if hasSchedule && time.Now().Before(scheduledAt) {
+                    patchLogger.Debug("patch outside maintenance window, will retry next check", "schedule_at", scheduledAt)
+                    continue
+                }
+
+                patch := patcher.Patch{
+                    ID:          asg.PatchID,
+                    Name:        asg.Name,
+                    Version:     asg.Version,
+                    Vendor:      asg.Vendor,
+                    Severity:    asg.Severity,
+                    Description: asg.Description,
+                    ScheduleAt:  scheduledAt,
+                }
+                reportPatchStatus(ctx, client, cfg, id, asg.PatchID, patcher.StatusInProgress, "", patcher.Result{}, patchLogger)
+                patchLogger.Info("installing patch", "name", asg.Name)
+
+                result, err := backend.Install(ctx, patch)
+                if err != nil {
+                    patchLogger.Error("patch install failed", "error", err)
+                    metrics.PatchesInstalled.WithLabelValues(patcher.StatusFailed).Inc()
+                    reportPatchStatus(ctx, client, cfg, id, asg.PatchID, patcher.StatusFailed, err.Error(), result, patchLogger)
+                    continue
+                }
+                if result.RequiresReboot {
+                    patchLogger.Info("patch installed, reboot required")
+                    metrics.PatchesInstalled.WithLabelValues(patcher.StatusRequiresReboot).Inc()
+                    reportPatchStatus(ctx, client, cfg, id, asg.PatchID, patcher.StatusRequiresReboot, "", result, patchLogger)
+                    installed++
+                    continue
+                }
+                if err := backend.Verify(ctx, patch); err != nil {
+                    // The install reported success but we can't confirm it
+                    // stuck; rolled_back tells the backend this device
+                    // needs to be re-assigned rather than treated as
+                    // patched.
+                    patchLogger.Warn("patch verification failed", "error", err)
+                    metrics.PatchesInstalled.WithLabelValues(patcher.StatusRolledBack).Inc()
+                    reportPatchStatus(ctx, client, cfg, id, asg.PatchID, patcher.StatusRolledBack, err.Error(), result, patchLogger)
+                    continue
+                }
+                patchLogger.Info("patch installed successfully")
+                metrics.PatchesInstalled.WithLabelValues(patcher.StatusSuccess).Inc()
+                installed++
+                reportPatchStatus(ctx, client, cfg, id, asg.PatchID, patcher.StatusSuccess, "", result, patchLogger)
             }
         }
     }
+    tracker.RecordPatchCheck(status.PatchCheck{Time: time.Now(), Installed: installed})
 }
 
-// reportPatchStatus posts a status update to the patch service.  It
-// ignores errors to avoid retry storms.
-func reportPatchStatus(deviceId string, patchId int, status string, errorMsg string) {
-    baseURL := os.Getenv("PATCH_SERVICE_URL")
-    if baseURL == "" {
-        baseURL = "http://localhost:3004/api/patch-status"
+// parseScheduleAt parses the assignment's schedule_at field, reporting
+// false if it's missing or not an RFC3339 timestamp.
+func parseScheduleAt(v interface{}) (time.Time, bool) {
+    s, ok := v.(string)
+    if !ok || s == "" {
+        return time.Time{}, false
     }
-    // If the env var is just a base path like http://.../api/patches
-    if strings.HasSuffix(baseURL, "/patches") {
-        baseURL = strings.TrimSuffix(baseURL, "/patches") + "/patch-status"
+    t, err := time.Parse(time.RFC3339, s)
+    if err != nil {
+        return time.Time{}, false
     }
+    return t, true
+}
+
+// reportPatchStatus posts a status update to the patch service, including
+// the backend's captured output and exit code.  It logs but otherwise
+// ignores errors: client already retries transient failures, and giving
+// up on a status update shouldn't block the install loop.
+func reportPatchStatus(ctx context.Context, client *httpx.Client, cfg config.Config, deviceId string, patchId int, status string, errorMsg string, result patcher.Result, logger logging.Logger) {
+    // cfg.PatchServiceURL is the assignments endpoint (".../api/patches");
+    // status updates go to its "patch-status" sibling.
+    baseURL := strings.TrimSuffix(cfg.PatchServiceURL, "/patches") + "/patch-status"
     body := map[string]interface{}{
-        "device_id":    deviceId,
-        "patch_id":     patchId,
-        "status":       status,
+        "device_id":     deviceId,
+        "patch_id":      patchId,
+        "status":        status,
         "error_message": errorMsg,
+        "output":        result.Output,
+        "exit_code":     result.ExitCode,
     }
     jsonBody, _ := json.Marshal(body)
-    req, err := http.NewRequest("POST", baseURL, bytes.NewReader(jsonBody))
-    if err != nil {
-        fmt.Printf("failed to create patch status request: %v\n", err)
-        return
-    }
-    req.Header.Set("Content-Type", "application/json")
-    client := &http.Client{Timeout: 15 * time.Second}
-    resp, err := client.Do(req)
+
+    resp, err := client.Do(ctx, "patch-status", 15*time.Second, func() (*http.Request, error) {
+        req, err := http.NewRequest("POST", baseURL, bytes.NewReader(jsonBody))
+        if err != nil {
+            return nil, err
+        }
+        req.Header.Set("Content-Type", "application/json")
+        return req, nil
+    })
     if err != nil {
-        fmt.Printf("failed to report patch status: %v\n", err)
+        logger.Error("failed to report patch status", "status", status, "error", err)
         return
     }
     resp.Body.Close()
-}
\ No newline at end of file
+}
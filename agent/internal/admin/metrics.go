@@ -0,0 +1,32 @@
+package admin
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics are the agent's own operational counters, exposed alongside
+// httpx.Client's request counters on /metrics. They're created once in
+// main and threaded into whichever service produces the corresponding
+// event.
+type Metrics struct {
+    RegistrationAttempts prometheus.Counter
+    PatchesInstalled     *prometheus.CounterVec
+    CollectorDuration    *prometheus.HistogramVec
+}
+
+// NewMetrics returns an unregistered Metrics; Server.Serve registers it
+// with the Prometheus registry it serves from /metrics.
+func NewMetrics() *Metrics {
+    return &Metrics{
+        RegistrationAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+            Name: "agent_registration_attempts_total",
+            Help: "Number of device registration attempts made by this agent.",
+        }),
+        PatchesInstalled: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Name: "agent_patches_installed_total",
+            Help: "Number of patches this agent has attempted to install, by outcome status.",
+        }, []string{"status"}),
+        CollectorDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Name: "agent_collector_duration_seconds",
+            Help: "Time taken by each collector to gather its sample.",
+        }, []string{"collector"}),
+    }
+}
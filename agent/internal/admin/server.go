@@ -0,0 +1,101 @@
+// Package admin exposes a loopback-only HTTP endpoint for operators and
+// monitoring systems to inspect a single running agent — liveness,
+// readiness, version, Prometheus metrics, and a snapshot of its last
+// collector ticks and patch check — without waiting on the backend.
+package admin
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net"
+    "net/http"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+
+    "github.com/younes-barhouni/cdots/agent/internal/httpx"
+    "github.com/younes-barhouni/cdots/agent/internal/logging"
+    "github.com/younes-barhouni/cdots/agent/internal/status"
+)
+
+// Server is the agent's admin HTTP endpoint. It implements
+// supervisor.Service so it restarts with backoff like any other agent
+// service if its listener dies.
+type Server struct {
+    Addr       string
+    Version    string
+    HTTPClient *httpx.Client
+    Metrics    *Metrics
+    Tracker    *status.Tracker
+    Logger     logging.Logger
+}
+
+// Serve listens on s.Addr until ctx is cancelled. It's expected to be
+// bound to a loopback address (e.g. 127.0.0.1:9110); callers are
+// responsible for not exposing it beyond the host.
+func (s *Server) Serve(ctx context.Context) error {
+    registry := prometheus.NewRegistry()
+    registry.MustRegister(s.HTTPClient.Attempts, s.HTTPClient.Failures)
+    registry.MustRegister(s.Metrics.RegistrationAttempts, s.Metrics.PatchesInstalled, s.Metrics.CollectorDuration)
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/healthz", s.handleHealthz)
+    mux.HandleFunc("/readyz", s.handleReadyz)
+    mux.HandleFunc("/version", s.handleVersion)
+    mux.HandleFunc("/status", s.handleStatus)
+    mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+    ln, err := net.Listen("tcp", s.Addr)
+    if err != nil {
+        return fmt.Errorf("admin: listen on %s: %w", s.Addr, err)
+    }
+
+    httpServer := &http.Server{Addr: s.Addr, Handler: mux}
+    errCh := make(chan error, 1)
+    go func() { errCh <- httpServer.Serve(ln) }()
+
+    s.Logger.Info("admin endpoint listening", "addr", s.Addr)
+
+    select {
+    case <-ctx.Done():
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        return httpServer.Shutdown(shutdownCtx)
+    case err := <-errCh:
+        if err != nil && err != http.ErrServerClosed {
+            return err
+        }
+        return nil
+    }
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+    w.WriteHeader(http.StatusOK)
+    w.Write([]byte("ok"))
+}
+
+// handleReadyz reports ready once the agent has completed device
+// registration.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+    if s.Tracker.DeviceID() == "" {
+        http.Error(w, "not registered", http.StatusServiceUnavailable)
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+    w.Write([]byte("ok"))
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, map[string]string{"version": s.Version})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, s.Tracker.Snapshot())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(v)
+}
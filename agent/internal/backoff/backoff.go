@@ -0,0 +1,36 @@
+// Package backoff implements jittered exponential backoff shared by the
+// supervisor's service-restart policy and the httpx retry client.
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy describes a jittered exponential backoff: the delay grows as
+// Base*Factor^attempt, capped at Max, then jittered into [0.5x, 1.0x) so
+// that many callers retrying the same backend don't land in lockstep.
+type Policy struct {
+	Base   time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+// Delay returns the backoff duration for the given zero-based attempt.
+func (p Policy) Delay(attempt int) time.Duration {
+	base := p.Base
+	if base <= 0 {
+		base = time.Second
+	}
+	factor := p.Factor
+	if factor <= 1 {
+		factor = 2
+	}
+	d := float64(base) * math.Pow(factor, float64(attempt))
+	if p.Max > 0 && d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	jitter := 0.5 + rand.Float64()/2
+	return time.Duration(d * jitter)
+}
@@ -0,0 +1,48 @@
+package backoff
+
+import (
+    "testing"
+    "time"
+)
+
+func TestDelayCapsAtMax(t *testing.T) {
+    p := Policy{Base: time.Second, Max: 5 * time.Second, Factor: 2}
+    // At attempt 10, Base*Factor^10 is far past Max, so even with the
+    // maximum jitter multiplier (1.0x) the delay must not exceed Max.
+    d := p.Delay(10)
+    if d > p.Max {
+        t.Fatalf("Delay(10) = %v, want <= Max (%v)", d, p.Max)
+    }
+}
+
+func TestDelayGrowsWithAttempt(t *testing.T) {
+    p := Policy{Base: time.Second, Max: time.Hour, Factor: 2}
+    // Compare the worst case of an early attempt against the best case
+    // of a later one, to stay clear of jitter overlap.
+    early := p.Delay(0)
+    late := p.Delay(5)
+    if late <= early {
+        t.Fatalf("Delay(5) = %v, want > Delay(0) = %v", late, early)
+    }
+}
+
+func TestDelayJitterRange(t *testing.T) {
+    p := Policy{Base: time.Second, Max: time.Hour, Factor: 2}
+    want := float64(time.Second)
+    for i := 0; i < 100; i++ {
+        d := float64(p.Delay(0))
+        if d < 0.5*want || d > want {
+            t.Fatalf("Delay(0) = %v, want within [0.5x, 1.0x] of base %v", time.Duration(d), p.Base)
+        }
+    }
+}
+
+func TestDelayDefaultsForZeroFields(t *testing.T) {
+    // A zero-value Policy shouldn't panic or return 0; Delay falls back
+    // to a 1s base and a factor of 2.
+    var p Policy
+    d := p.Delay(0)
+    if d <= 0 || d > time.Second {
+        t.Fatalf("Delay(0) on zero-value Policy = %v, want in (0, 1s]", d)
+    }
+}
@@ -0,0 +1,99 @@
+// Package collector defines the agent's metrics pipeline: a set of
+// pluggable Collectors gathered concurrently on every tick by a
+// Registry, whose combined Samples are forwarded to a pluggable Sink.
+package collector
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// Sample is one collector's reading for a single tick.
+type Sample struct {
+    Collector string             `json:"collector"`
+    Timestamp time.Time          `json:"timestamp"`
+    Fields    map[string]float64 `json:"fields"`
+}
+
+// Collector produces one Sample per call. Collect should respect ctx and
+// return promptly if it is cancelled.
+type Collector interface {
+    Name() string
+    Collect(ctx context.Context) (Sample, error)
+}
+
+// Registry runs a fixed set of Collectors concurrently on every tick,
+// each bounded by its own timeout, and forwards the successful samples
+// to a Sink in one batch.
+type Registry struct {
+    sink       Sink
+    timeout    time.Duration
+    onError    func(collector string, err error)
+    onTick     func(collector string, duration time.Duration, err error)
+    collectors []Collector
+}
+
+// New returns a Registry that sends samples to sink, giving each
+// collector up to timeout per tick before its result is dropped.
+func New(sink Sink, timeout time.Duration) *Registry {
+    return &Registry{sink: sink, timeout: timeout}
+}
+
+// OnError installs fn to be called whenever a collector errors or times
+// out, so callers can log without Registry depending on a logging
+// package.
+func (r *Registry) OnError(fn func(collector string, err error)) {
+    r.onError = fn
+}
+
+// OnTick installs fn to be called after every collector run, success or
+// failure, with how long it took, so callers can track freshness and
+// timing without inspecting the samples handed to the sink.
+func (r *Registry) OnTick(fn func(collector string, duration time.Duration, err error)) {
+    r.onTick = fn
+}
+
+// Register adds c to the set of collectors run on every tick.
+func (r *Registry) Register(c Collector) {
+    r.collectors = append(r.collectors, c)
+}
+
+// CollectAndSend runs every registered collector concurrently, waits for
+// them all to finish or time out, and hands the successful samples to
+// the sink as a single batch.
+func (r *Registry) CollectAndSend(ctx context.Context) error {
+    var (
+        mu      sync.Mutex
+        wg      sync.WaitGroup
+        samples = make([]Sample, 0, len(r.collectors))
+    )
+    for _, c := range r.collectors {
+        c := c
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            cctx, cancel := context.WithTimeout(ctx, r.timeout)
+            defer cancel()
+            start := time.Now()
+            sample, err := c.Collect(cctx)
+            if r.onTick != nil {
+                r.onTick(c.Name(), time.Since(start), err)
+            }
+            if err != nil {
+                if r.onError != nil {
+                    r.onError(c.Name(), err)
+                }
+                return
+            }
+            mu.Lock()
+            samples = append(samples, sample)
+            mu.Unlock()
+        }()
+    }
+    wg.Wait()
+    if len(samples) == 0 {
+        return nil
+    }
+    return r.sink.Send(ctx, samples)
+}
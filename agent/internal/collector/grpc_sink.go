@@ -0,0 +1,82 @@
+package collector
+
+import (
+    "context"
+    "crypto/tls"
+    "encoding/json"
+    "fmt"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/encoding"
+)
+
+// sendSamplesMethod is the telemetry ingest service's unary RPC for
+// delivering a batch of samples.
+const sendSamplesMethod = "/cdots.telemetry.TelemetryService/SendSamples"
+
+// jsonCodecName registers a JSON wire codec under the "json" content
+// subtype, so GRPCSink can call the telemetry service without generated
+// protobuf stubs while its message shape is still in flux.
+const jsonCodecName = "json"
+
+func init() {
+    encoding.RegisterCodec(jsonCodec{})
+}
+
+// GRPCSink forwards samples to the telemetry ingest service over a gRPC
+// connection.
+type GRPCSink struct {
+    conn *grpc.ClientConn
+}
+
+// NewGRPCSink dials target (host:port) and returns a Sink that sends
+// samples via unary gRPC calls. If tlsConfig is nil, the connection is
+// unencrypted; callers should only omit it for a loopback or otherwise
+// trusted target.
+func NewGRPCSink(target string, tlsConfig *tls.Config) (*GRPCSink, error) {
+    transportCreds := insecure.NewCredentials()
+    if tlsConfig != nil {
+        transportCreds = credentials.NewTLS(tlsConfig)
+    }
+    conn, err := grpc.NewClient(target,
+        grpc.WithTransportCredentials(transportCreds),
+        grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodecName)),
+    )
+    if err != nil {
+        return nil, fmt.Errorf("dial telemetry service: %w", err)
+    }
+    return &GRPCSink{conn: conn}, nil
+}
+
+func (s *GRPCSink) Send(ctx context.Context, samples []Sample) error {
+    req := sendSamplesRequest{Samples: samples}
+    var resp sendSamplesResponse
+    return s.conn.Invoke(ctx, sendSamplesMethod, &req, &resp)
+}
+
+// Close releases the underlying gRPC connection.
+func (s *GRPCSink) Close() error {
+    return s.conn.Close()
+}
+
+type sendSamplesRequest struct {
+    Samples []Sample `json:"samples"`
+}
+
+type sendSamplesResponse struct{}
+
+// jsonCodec implements encoding.Codec using the standard library's JSON
+// marshaler.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return jsonCodecName }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+    return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+    return json.Unmarshal(data, v)
+}
@@ -0,0 +1,45 @@
+package collector
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/younes-barhouni/cdots/agent/internal/httpx"
+)
+
+// HTTPSink posts samples as a JSON array to a metrics-ingest endpoint.
+type HTTPSink struct {
+    URL     string
+    Client  *httpx.Client
+    Timeout time.Duration
+}
+
+// NewHTTPSink returns a Sink that POSTs samples to url using client's
+// retry policy.
+func NewHTTPSink(url string, client *httpx.Client) *HTTPSink {
+    return &HTTPSink{URL: url, Client: client, Timeout: 15 * time.Second}
+}
+
+func (s *HTTPSink) Send(ctx context.Context, samples []Sample) error {
+    body, err := json.Marshal(samples)
+    if err != nil {
+        return fmt.Errorf("marshal samples: %w", err)
+    }
+    resp, err := s.Client.Do(ctx, "metrics-http", s.Timeout, func() (*http.Request, error) {
+        req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+        if err != nil {
+            return nil, err
+        }
+        req.Header.Set("Content-Type", "application/json")
+        return req, nil
+    })
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    return nil
+}
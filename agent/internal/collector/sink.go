@@ -0,0 +1,8 @@
+package collector
+
+import "context"
+
+// Sink delivers a batch of Samples to wherever metrics are consumed.
+type Sink interface {
+    Send(ctx context.Context, samples []Sample) error
+}
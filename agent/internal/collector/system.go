@@ -0,0 +1,80 @@
+package collector
+
+import (
+    "context"
+    "time"
+
+    "github.com/shirou/gopsutil/v3/cpu"
+    "github.com/shirou/gopsutil/v3/disk"
+    "github.com/shirou/gopsutil/v3/mem"
+    "github.com/shirou/gopsutil/v3/net"
+)
+
+// CPUCollector reports overall CPU utilisation as a percentage.
+type CPUCollector struct{}
+
+func (CPUCollector) Name() string { return "cpu" }
+
+func (CPUCollector) Collect(ctx context.Context) (Sample, error) {
+    percents, err := cpu.PercentWithContext(ctx, 0, false)
+    if err != nil {
+        return Sample{}, err
+    }
+    var pct float64
+    if len(percents) > 0 {
+        pct = percents[0]
+    }
+    return Sample{Collector: "cpu", Timestamp: time.Now(), Fields: map[string]float64{"percent": pct}}, nil
+}
+
+// MemoryCollector reports RAM utilisation as a percentage.
+type MemoryCollector struct{}
+
+func (MemoryCollector) Name() string { return "memory" }
+
+func (MemoryCollector) Collect(ctx context.Context) (Sample, error) {
+    vm, err := mem.VirtualMemoryWithContext(ctx)
+    if err != nil {
+        return Sample{}, err
+    }
+    return Sample{Collector: "memory", Timestamp: time.Now(), Fields: map[string]float64{"percent": vm.UsedPercent}}, nil
+}
+
+// DiskCollector reports disk utilisation for Path (default "/") as a
+// percentage.
+type DiskCollector struct {
+    Path string
+}
+
+func (DiskCollector) Name() string { return "disk" }
+
+func (c DiskCollector) Collect(ctx context.Context) (Sample, error) {
+    path := c.Path
+    if path == "" {
+        path = "/"
+    }
+    usage, err := disk.UsageWithContext(ctx, path)
+    if err != nil {
+        return Sample{}, err
+    }
+    return Sample{Collector: "disk", Timestamp: time.Now(), Fields: map[string]float64{"percent": usage.UsedPercent}}, nil
+}
+
+// NetworkCollector reports cumulative bytes sent/received across all
+// interfaces.
+type NetworkCollector struct{}
+
+func (NetworkCollector) Name() string { return "network" }
+
+func (NetworkCollector) Collect(ctx context.Context) (Sample, error) {
+    counters, err := net.IOCountersWithContext(ctx, false)
+    if err != nil {
+        return Sample{}, err
+    }
+    fields := map[string]float64{}
+    if len(counters) > 0 {
+        fields["bytes_sent"] = float64(counters[0].BytesSent)
+        fields["bytes_recv"] = float64(counters[0].BytesRecv)
+    }
+    return Sample{Collector: "network", Timestamp: time.Now(), Fields: fields}, nil
+}
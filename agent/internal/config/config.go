@@ -0,0 +1,320 @@
+// Package config loads the agent's configuration from a YAML file,
+// overlaid by environment variables and then CLI flags (in that
+// precedence order, so flags always win), replacing the scattered
+// os.Getenv calls and package-level var defaults the agent used to rely
+// on.
+package config
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/younes-barhouni/cdots/agent/internal/backoff"
+)
+
+// Duration is a time.Duration that unmarshals from YAML as either a
+// Go duration string ("30s", "1h") or a plain integer of nanoseconds.
+type Duration time.Duration
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+    var n int64
+    if err := value.Decode(&n); err == nil {
+        *d = Duration(n)
+        return nil
+    }
+    var s string
+    if err := value.Decode(&s); err != nil {
+        return err
+    }
+    parsed, err := time.ParseDuration(s)
+    if err != nil {
+        return fmt.Errorf("invalid duration %q: %w", s, err)
+    }
+    *d = Duration(parsed)
+    return nil
+}
+
+// RetryConfig configures the httpx.Client retry policy.
+type RetryConfig struct {
+    MaxAttempts  int      `yaml:"max_attempts"`
+    BaseDelay    Duration `yaml:"base_delay"`
+    MaxDelay     Duration `yaml:"max_delay"`
+    Budget       int      `yaml:"budget"`
+    BudgetWindow Duration `yaml:"budget_window"`
+}
+
+// Backoff converts r into the backoff.Policy used by httpx.Client.
+func (r RetryConfig) Backoff() backoff.Policy {
+    return backoff.Policy{Base: time.Duration(r.BaseDelay), Max: time.Duration(r.MaxDelay), Factor: 2}
+}
+
+// TLSConfig names the client certificate material used when talking to
+// the backend services.
+type TLSConfig struct {
+    CertFile string `yaml:"cert_file"`
+    KeyFile  string `yaml:"key_file"`
+    CAFile   string `yaml:"ca_file"`
+}
+
+// TLSConfig builds a *tls.Config from c's cert/key/CA files, or returns
+// (nil, nil) if none of them are set, meaning callers should fall back
+// to their own default (plain TLS verification for httpx.Client,
+// insecure for the gRPC sink, which is opt-in and loopback-adjacent by
+// default).
+func (c TLSConfig) TLSConfig() (*tls.Config, error) {
+    if c.CertFile == "" && c.KeyFile == "" && c.CAFile == "" {
+        return nil, nil
+    }
+    tlsCfg := &tls.Config{}
+    if c.CertFile != "" || c.KeyFile != "" {
+        cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+        if err != nil {
+            return nil, fmt.Errorf("load client certificate: %w", err)
+        }
+        tlsCfg.Certificates = []tls.Certificate{cert}
+    }
+    if c.CAFile != "" {
+        pem, err := os.ReadFile(c.CAFile)
+        if err != nil {
+            return nil, fmt.Errorf("read CA file: %w", err)
+        }
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(pem) {
+            return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+        }
+        tlsCfg.RootCAs = pool
+    }
+    return tlsCfg, nil
+}
+
+// Config holds every tunable knob for the agent.
+type Config struct {
+    DeviceServiceURL   string        `yaml:"device_service_url"`
+    PatchServiceURL    string        `yaml:"patch_service_url"`
+    MetricsServiceURL  string        `yaml:"metrics_service_url"`
+    MetricsSink        string        `yaml:"metrics_sink"`
+    MetricsServiceAddr string      `yaml:"metrics_service_addr"`
+    MetricsInterval    Duration    `yaml:"metrics_interval"`
+    PatchInterval      Duration    `yaml:"patch_interval"`
+    PatchDryRun        bool        `yaml:"patch_dry_run"`
+    EnabledCollectors  []string    `yaml:"enabled_collectors"`
+    LogLevel           string      `yaml:"log_level"`
+    LogFormat          string      `yaml:"log_format"`
+    AdminEnabled       bool        `yaml:"admin_enabled"`
+    AdminAddr          string      `yaml:"admin_addr"`
+    TLS                TLSConfig   `yaml:"tls"`
+    Retry              RetryConfig `yaml:"retry"`
+}
+
+// Default returns the configuration the agent used to have hard-coded.
+func Default() Config {
+    return Config{
+        DeviceServiceURL:   "http://localhost:3001/api/devices/register",
+        PatchServiceURL:    "http://localhost:3004/api/patches",
+        MetricsServiceURL:  "http://localhost:3001/api/metrics",
+        MetricsSink:        "http",
+        MetricsServiceAddr: "localhost:3005",
+        MetricsInterval:    Duration(30 * time.Second),
+        PatchInterval:      Duration(60 * time.Minute),
+        EnabledCollectors:  []string{"cpu", "memory", "disk", "network"},
+        LogLevel:           "info",
+        LogFormat:          "text",
+        AdminEnabled:       false,
+        AdminAddr:          "127.0.0.1:9110",
+        Retry: RetryConfig{
+            MaxAttempts:  5,
+            BaseDelay:    Duration(500 * time.Millisecond),
+            MaxDelay:     Duration(30 * time.Second),
+            Budget:       20,
+            BudgetWindow: Duration(time.Minute),
+        },
+    }
+}
+
+// Load builds the agent's Config from Default(), a YAML file (if one is
+// found), environment variables and args (typically os.Args[1:]), in
+// that overlay order, then validates the result.
+func Load(args []string) (Config, error) {
+    fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+    configPath := fs.String("config", "", "path to agent.yaml (overrides the default search path)")
+    logLevel := fs.String("log-level", "", "log level override (trace|debug|info|warn|error)")
+    logFormat := fs.String("log-format", "", "log format override (text|json)")
+    deviceServiceURL := fs.String("device-service-url", "", "device registration endpoint")
+    patchServiceURL := fs.String("patch-service-url", "", "patch assignment/status endpoint")
+    patchInterval := fs.String("patch-interval", "", "how often to check for patches, e.g. 60m")
+    patchDryRun := fs.Bool("patch-dry-run", false, "log patch installs instead of running them")
+    adminEnabled := fs.Bool("admin-enabled", false, "expose the local admin HTTP endpoint (health, version, metrics, status)")
+    adminAddr := fs.String("admin-addr", "", "address for the admin endpoint, e.g. 127.0.0.1:9110")
+    if err := fs.Parse(args); err != nil {
+        return Config{}, err
+    }
+
+    cfg := Default()
+
+    if path := findConfigFile(*configPath); path != "" {
+        if err := mergeFile(&cfg, path); err != nil {
+            return Config{}, fmt.Errorf("load config file %s: %w", path, err)
+        }
+    }
+
+    mergeEnv(&cfg)
+
+    fs.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "log-level":
+            cfg.LogLevel = *logLevel
+        case "log-format":
+            cfg.LogFormat = *logFormat
+        case "device-service-url":
+            cfg.DeviceServiceURL = *deviceServiceURL
+        case "patch-service-url":
+            cfg.PatchServiceURL = *patchServiceURL
+        case "patch-interval":
+            if d, err := time.ParseDuration(*patchInterval); err == nil {
+                cfg.PatchInterval = Duration(d)
+            }
+        case "patch-dry-run":
+            cfg.PatchDryRun = *patchDryRun
+        case "admin-enabled":
+            cfg.AdminEnabled = *adminEnabled
+        case "admin-addr":
+            cfg.AdminAddr = *adminAddr
+        }
+    })
+
+    if err := cfg.Validate(); err != nil {
+        return Config{}, err
+    }
+    return cfg, nil
+}
+
+// findConfigFile resolves the config file to load: an explicit --config
+// path takes precedence, then $XDG_CONFIG_HOME/cdots/agent.yaml, then
+// /etc/cdots/agent.yaml. It returns "" if none exist.
+func findConfigFile(explicit string) string {
+    if explicit != "" {
+        return explicit
+    }
+    if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+        if p := filepath.Join(xdg, "cdots", "agent.yaml"); fileExists(p) {
+            return p
+        }
+    }
+    const systemPath = "/etc/cdots/agent.yaml"
+    if fileExists(systemPath) {
+        return systemPath
+    }
+    return ""
+}
+
+func fileExists(path string) bool {
+    info, err := os.Stat(path)
+    return err == nil && !info.IsDir()
+}
+
+func mergeFile(cfg *Config, path string) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+    return yaml.Unmarshal(data, cfg)
+}
+
+// mergeEnv overlays the environment variables the agent previously read
+// ad hoc from registerDevice/checkForPatches/reportPatchStatus and the
+// main loop, plus the new metrics sink, dry-run, logging, admin and TLS
+// knobs. The retry policy is not read from the environment; it comes
+// from the defaults or the config file only.
+func mergeEnv(cfg *Config) {
+    if v := os.Getenv("DEVICE_SERVICE_URL"); v != "" {
+        cfg.DeviceServiceURL = v
+    }
+    if v := os.Getenv("PATCH_SERVICE_URL"); v != "" {
+        cfg.PatchServiceURL = v
+    }
+    if v := os.Getenv("PATCH_INTERVAL_MINUTES"); v != "" {
+        if d, err := time.ParseDuration(v + "m"); err == nil {
+            cfg.PatchInterval = Duration(d)
+        }
+    }
+    if v := os.Getenv("METRICS_SERVICE_URL"); v != "" {
+        cfg.MetricsServiceURL = v
+    }
+    if v := os.Getenv("METRICS_SINK"); v != "" {
+        cfg.MetricsSink = v
+    }
+    if v := os.Getenv("METRICS_SERVICE_ADDR"); v != "" {
+        cfg.MetricsServiceAddr = v
+    }
+    if v := os.Getenv("AGENT_PATCH_DRY_RUN"); v != "" {
+        if b, err := strconv.ParseBool(v); err == nil {
+            cfg.PatchDryRun = b
+        }
+    }
+    if v := os.Getenv("LOG_LEVEL"); v != "" {
+        cfg.LogLevel = v
+    }
+    if v := os.Getenv("LOG_FORMAT"); v != "" {
+        cfg.LogFormat = v
+    }
+    if v := os.Getenv("ADMIN_ENABLED"); v != "" {
+        if b, err := strconv.ParseBool(v); err == nil {
+            cfg.AdminEnabled = b
+        }
+    }
+    if v := os.Getenv("ADMIN_ADDR"); v != "" {
+        cfg.AdminAddr = v
+    }
+    if v := os.Getenv("TLS_CERT_FILE"); v != "" {
+        cfg.TLS.CertFile = v
+    }
+    if v := os.Getenv("TLS_KEY_FILE"); v != "" {
+        cfg.TLS.KeyFile = v
+    }
+    if v := os.Getenv("TLS_CA_FILE"); v != "" {
+        cfg.TLS.CAFile = v
+    }
+}
+
+// Validate checks that cfg is internally consistent, so the agent fails
+// fast with a readable error instead of misbehaving at runtime.
+func (c Config) Validate() error {
+    var problems []string
+    if c.DeviceServiceURL == "" {
+        problems = append(problems, "device_service_url must not be empty")
+    }
+    if c.PatchServiceURL == "" {
+        problems = append(problems, "patch_service_url must not be empty")
+    }
+    if c.MetricsInterval <= 0 {
+        problems = append(problems, "metrics_interval must be positive")
+    }
+    if c.PatchInterval <= 0 {
+        problems = append(problems, "patch_interval must be positive")
+    }
+    if c.Retry.MaxAttempts <= 0 {
+        problems = append(problems, "retry.max_attempts must be positive")
+    }
+    if c.AdminEnabled && c.AdminAddr == "" {
+        problems = append(problems, "admin_addr must not be empty when admin_enabled is true")
+    }
+    switch strings.ToLower(c.MetricsSink) {
+    case "http", "grpc":
+    default:
+        problems = append(problems, fmt.Sprintf("metrics_sink must be \"http\" or \"grpc\", got %q", c.MetricsSink))
+    }
+    if len(problems) > 0 {
+        return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+    }
+    return nil
+}
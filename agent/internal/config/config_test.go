@@ -0,0 +1,124 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// clearEnv sets every env var mergeEnv reads to "" (mergeEnv treats ""
+// as unset), so tests don't depend on the ambient environment and
+// t.Setenv's automatic restore keeps them isolated from each other.
+func clearEnv(t *testing.T) {
+    t.Helper()
+    for _, v := range []string{
+        "XDG_CONFIG_HOME",
+        "DEVICE_SERVICE_URL", "PATCH_SERVICE_URL", "PATCH_INTERVAL_MINUTES",
+        "METRICS_SERVICE_URL", "METRICS_SINK", "METRICS_SERVICE_ADDR",
+        "AGENT_PATCH_DRY_RUN", "LOG_LEVEL", "LOG_FORMAT",
+        "ADMIN_ENABLED", "ADMIN_ADDR",
+        "TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_CA_FILE",
+    } {
+        t.Setenv(v, "")
+    }
+}
+
+func TestLoadDefaultsWithNoFileEnvOrFlags(t *testing.T) {
+    clearEnv(t)
+    cfg, err := Load(nil)
+    if err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    want := Default()
+    if cfg.DeviceServiceURL != want.DeviceServiceURL || cfg.PatchServiceURL != want.PatchServiceURL {
+        t.Fatalf("Load() = %+v, want defaults %+v", cfg, want)
+    }
+}
+
+func TestLoadPrecedenceFileThenEnvThenFlag(t *testing.T) {
+    clearEnv(t)
+    dir := t.TempDir()
+    path := filepath.Join(dir, "agent.yaml")
+    if err := os.WriteFile(path, []byte("device_service_url: http://from-file\n"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+
+    // File alone.
+    cfg, err := Load([]string{"--config", path})
+    if err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    if cfg.DeviceServiceURL != "http://from-file" {
+        t.Fatalf("DeviceServiceURL = %q, want file value", cfg.DeviceServiceURL)
+    }
+
+    // Env overrides file.
+    t.Setenv("DEVICE_SERVICE_URL", "http://from-env")
+    cfg, err = Load([]string{"--config", path})
+    if err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    if cfg.DeviceServiceURL != "http://from-env" {
+        t.Fatalf("DeviceServiceURL = %q, want env value to override file", cfg.DeviceServiceURL)
+    }
+
+    // Flag overrides env (and file).
+    cfg, err = Load([]string{"--config", path, "--device-service-url", "http://from-flag"})
+    if err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    if cfg.DeviceServiceURL != "http://from-flag" {
+        t.Fatalf("DeviceServiceURL = %q, want flag value to override env and file", cfg.DeviceServiceURL)
+    }
+}
+
+func TestLoadPatchIntervalFlagParsesDuration(t *testing.T) {
+    clearEnv(t)
+    cfg, err := Load([]string{"--patch-interval", "15m"})
+    if err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    if time.Duration(cfg.PatchInterval) != 15*time.Minute {
+        t.Fatalf("PatchInterval = %v, want 15m", time.Duration(cfg.PatchInterval))
+    }
+}
+
+func TestValidateRejectsEmptyURLs(t *testing.T) {
+    cfg := Default()
+    cfg.DeviceServiceURL = ""
+    if err := cfg.Validate(); err == nil {
+        t.Fatal("Validate() = nil, want error for empty device_service_url")
+    }
+}
+
+func TestValidateRejectsNonPositiveIntervals(t *testing.T) {
+    cfg := Default()
+    cfg.MetricsInterval = 0
+    if err := cfg.Validate(); err == nil {
+        t.Fatal("Validate() = nil, want error for zero metrics_interval")
+    }
+}
+
+func TestValidateRejectsUnknownMetricsSink(t *testing.T) {
+    cfg := Default()
+    cfg.MetricsSink = "carrier-pigeon"
+    if err := cfg.Validate(); err == nil {
+        t.Fatal("Validate() = nil, want error for unknown metrics_sink")
+    }
+}
+
+func TestValidateRejectsAdminEnabledWithoutAddr(t *testing.T) {
+    cfg := Default()
+    cfg.AdminEnabled = true
+    cfg.AdminAddr = ""
+    if err := cfg.Validate(); err == nil {
+        t.Fatal("Validate() = nil, want error when admin_enabled is true and admin_addr is empty")
+    }
+}
+
+func TestValidateAcceptsDefaults(t *testing.T) {
+    if err := Default().Validate(); err != nil {
+        t.Fatalf("Validate() on Default() = %v, want nil", err)
+    }
+}
@@ -0,0 +1,179 @@
+// Package httpx wraps http.Client with jittered exponential backoff
+// retries for the idempotent calls the agent makes to its backend
+// services (device registration, patch status reporting).
+package httpx
+
+import (
+    "context"
+    "crypto/tls"
+    "fmt"
+    "io"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+
+    "github.com/younes-barhouni/cdots/agent/internal/backoff"
+)
+
+// Config controls retry behaviour for a Client.
+type Config struct {
+    // MaxAttempts is the total number of tries per call, including the
+    // first one.
+    MaxAttempts int
+    Backoff     backoff.Policy
+
+    // RetryBudget caps the number of retries (i.e. attempts beyond the
+    // first) a Client will spend within RetryBudgetWindow, across all
+    // calls, so a flapping backend can't turn every device into a retry
+    // storm against it.
+    RetryBudget       int
+    RetryBudgetWindow time.Duration
+
+    // TLSClientConfig configures client certificates/CA verification
+    // for HTTPS calls. Nil uses net/http's defaults.
+    TLSClientConfig *tls.Config
+}
+
+// DefaultConfig is a sensible retry policy for talking to the device and
+// patch services: a handful of attempts, capped delay, and a modest
+// per-minute retry budget.
+func DefaultConfig() Config {
+    return Config{
+        MaxAttempts:       5,
+        Backoff:           backoff.Policy{Base: 500 * time.Millisecond, Max: 30 * time.Second, Factor: 2},
+        RetryBudget:       20,
+        RetryBudgetWindow: time.Minute,
+    }
+}
+
+// Client retries idempotent HTTP calls on network errors and 5xx
+// responses. It is safe for concurrent use.
+type Client struct {
+    HTTPClient *http.Client
+    Config     Config
+
+    Attempts *prometheus.CounterVec
+    Failures *prometheus.CounterVec
+
+    mu           sync.Mutex
+    budgetUsed   int
+    windowStart  time.Time
+}
+
+// New returns a Client configured with cfg. Its Attempts and Failures
+// counters are unregistered; callers that expose them via an admin/metrics
+// endpoint are responsible for registering them with a prometheus.Registerer.
+func New(cfg Config) *Client {
+    var transport http.RoundTripper
+    if cfg.TLSClientConfig != nil {
+        transport = &http.Transport{TLSClientConfig: cfg.TLSClientConfig}
+    }
+    return &Client{
+        HTTPClient: &http.Client{Transport: transport},
+        Config:     cfg,
+        Attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Name: "agent_http_attempts_total",
+            Help: "Number of HTTP requests attempted per endpoint, including retries.",
+        }, []string{"endpoint"}),
+        Failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Name: "agent_http_failures_total",
+            Help: "Number of HTTP requests that failed (network error or 5xx) per endpoint.",
+        }, []string{"endpoint"}),
+    }
+}
+
+// NewRequestFunc builds a fresh *http.Request for a single attempt. It is
+// a func rather than a plain *http.Request because a request's body
+// reader is consumed on first use and must be rebuilt for every retry.
+type NewRequestFunc func() (*http.Request, error)
+
+// Do executes newRequest, retrying on network errors and 5xx responses
+// according to c.Config. endpoint is a short, low-cardinality label (e.g.
+// "device-register") used for the Attempts/Failures counters. timeout
+// bounds each individual attempt; ctx bounds the call as a whole.
+//
+// On success, the caller owns the returned response and must close its
+// body.
+func (c *Client) Do(ctx context.Context, endpoint string, timeout time.Duration, newRequest NewRequestFunc) (*http.Response, error) {
+    var lastErr error
+    for attempt := 0; attempt < c.maxAttempts(); attempt++ {
+        if attempt > 0 {
+            if !c.takeBudget() {
+                return nil, fmt.Errorf("%s: retry budget exhausted: %w", endpoint, lastErr)
+            }
+            select {
+            case <-time.After(c.Config.Backoff.Delay(attempt - 1)):
+            case <-ctx.Done():
+                return nil, ctx.Err()
+            }
+        }
+
+        req, err := newRequest()
+        if err != nil {
+            return nil, err
+        }
+        attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+        req = req.WithContext(attemptCtx)
+
+        c.Attempts.WithLabelValues(endpoint).Inc()
+        resp, err := c.HTTPClient.Do(req)
+        if err != nil {
+            cancel()
+            lastErr = err
+            c.Failures.WithLabelValues(endpoint).Inc()
+            continue
+        }
+        if resp.StatusCode >= 500 {
+            lastErr = fmt.Errorf("server error: %s", resp.Status)
+            resp.Body.Close()
+            cancel()
+            c.Failures.WithLabelValues(endpoint).Inc()
+            continue
+        }
+        resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+        return resp, nil
+    }
+    return nil, fmt.Errorf("%s: giving up after %d attempts: %w", endpoint, c.maxAttempts(), lastErr)
+}
+
+// cancelOnClose releases an attempt's timeout context when the caller
+// closes the response body, so the body stays readable until then.
+type cancelOnClose struct {
+    io.ReadCloser
+    cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+    err := b.ReadCloser.Close()
+    b.cancel()
+    return err
+}
+
+func (c *Client) maxAttempts() int {
+    if c.Config.MaxAttempts <= 0 {
+        return 1
+    }
+    return c.Config.MaxAttempts
+}
+
+// takeBudget reports whether a retry may proceed, consuming one unit of
+// the retry budget if so. The budget resets every RetryBudgetWindow.
+func (c *Client) takeBudget() bool {
+    if c.Config.RetryBudget <= 0 {
+        return true
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    now := time.Now()
+    if now.Sub(c.windowStart) > c.Config.RetryBudgetWindow {
+        c.windowStart = now
+        c.budgetUsed = 0
+    }
+    if c.budgetUsed >= c.Config.RetryBudget {
+        return false
+    }
+    c.budgetUsed++
+    return true
+}
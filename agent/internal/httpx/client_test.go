@@ -0,0 +1,43 @@
+package httpx
+
+import (
+    "testing"
+    "time"
+)
+
+func TestTakeBudgetUnlimitedWhenBudgetIsZero(t *testing.T) {
+    c := New(Config{RetryBudget: 0})
+    for i := 0; i < 100; i++ {
+        if !c.takeBudget() {
+            t.Fatalf("takeBudget() = false at iteration %d, want true (unlimited budget)", i)
+        }
+    }
+}
+
+func TestTakeBudgetExhaustsWithinWindow(t *testing.T) {
+    c := New(Config{RetryBudget: 2, RetryBudgetWindow: time.Minute})
+    if !c.takeBudget() {
+        t.Fatal("1st takeBudget() = false, want true")
+    }
+    if !c.takeBudget() {
+        t.Fatal("2nd takeBudget() = false, want true")
+    }
+    if c.takeBudget() {
+        t.Fatal("3rd takeBudget() = true, want false (budget exhausted)")
+    }
+}
+
+func TestTakeBudgetResetsAfterWindowElapses(t *testing.T) {
+    c := New(Config{RetryBudget: 1, RetryBudgetWindow: time.Minute})
+    if !c.takeBudget() {
+        t.Fatal("1st takeBudget() = false, want true")
+    }
+    if c.takeBudget() {
+        t.Fatal("2nd takeBudget() within the window = true, want false")
+    }
+    // Simulate the window having elapsed.
+    c.windowStart = time.Now().Add(-2 * time.Minute)
+    if !c.takeBudget() {
+        t.Fatal("takeBudget() after window reset = false, want true")
+    }
+}
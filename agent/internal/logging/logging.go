@@ -0,0 +1,38 @@
+// Package logging configures the agent's structured logger. It wraps
+// hashicorp/go-hclog so log lines can be shipped as JSON to log
+// aggregation (ELK/Loki) or as human-readable text on a terminal. Each
+// agent service logs through a sub-logger named after its component
+// (e.g. "agent.metrics"), and stable fields such as device_id, patch_id
+// and attempt are attached to the lines where they are known.
+package logging
+
+import (
+    "strings"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// Logger is the structured logger used throughout the agent.
+type Logger = hclog.Logger
+
+// New builds the root Logger. level and format normally come from
+// config.Config (which itself overlays LOG_LEVEL/LOG_FORMAT and
+// --log-level/--log-format); format is "json" or anything else for
+// text.
+func New(name string, level string, format string) Logger {
+    return hclog.New(&hclog.LoggerOptions{
+        Name:       name,
+        Level:      LevelFromString(level),
+        JSONFormat: strings.EqualFold(format, "json"),
+    })
+}
+
+// LevelFromString parses s as an hclog level, falling back to Info for an
+// empty or unrecognised value so a bad LOG_LEVEL never silences the
+// agent.
+func LevelFromString(s string) hclog.Level {
+    if lvl := hclog.LevelFromString(s); lvl != hclog.NoLevel {
+        return lvl
+    }
+    return hclog.Info
+}
@@ -0,0 +1,75 @@
+package patcher
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "os/exec"
+    "regexp"
+    "strings"
+)
+
+// DarwinBackend installs Apple security updates via softwareupdate, or
+// Homebrew formulae via brew upgrade for patches whose Vendor is
+// "homebrew".
+type DarwinBackend struct{}
+
+func (DarwinBackend) Name() string { return "softwareupdate" }
+
+// softwareupdateLabel matches a "* Label: <name>" line from
+// "softwareupdate --list" output, which is the identifier
+// "softwareupdate --install" expects back.
+var softwareupdateLabel = regexp.MustCompile(`^\s*\*\s*Label:\s*(\S+)`)
+
+func (DarwinBackend) List(ctx context.Context) ([]Patch, error) {
+    out, err := exec.CommandContext(ctx, "softwareupdate", "--list").CombinedOutput()
+    if err != nil {
+        return nil, fmt.Errorf("list software updates: %w: %s", err, out)
+    }
+    var patches []Patch
+    for _, line := range strings.Split(string(out), "\n") {
+        m := softwareupdateLabel.FindStringSubmatch(line)
+        if m == nil {
+            continue
+        }
+        patches = append(patches, Patch{Name: m[1]})
+    }
+    return patches, nil
+}
+
+func (DarwinBackend) Install(ctx context.Context, patch Patch) (Result, error) {
+    var cmd *exec.Cmd
+    if strings.EqualFold(patch.Vendor, "homebrew") {
+        cmd = exec.CommandContext(ctx, "brew", "upgrade", patch.Name)
+    } else {
+        cmd = exec.CommandContext(ctx, "softwareupdate", "--install", patch.Name)
+    }
+    var buf bytes.Buffer
+    cmd.Stdout = &buf
+    cmd.Stderr = &buf
+    err := cmd.Run()
+    result := Result{Output: buf.String(), ExitCode: exitCode(err)}
+    if err != nil {
+        return result, fmt.Errorf("install %s: %w", patch.Name, err)
+    }
+    result.RequiresReboot = bytes.Contains(buf.Bytes(), []byte("restart"))
+    return result, nil
+}
+
+func (DarwinBackend) Verify(ctx context.Context, patch Patch) error {
+    if strings.EqualFold(patch.Vendor, "homebrew") {
+        out, err := exec.CommandContext(ctx, "brew", "list", "--versions", patch.Name).CombinedOutput()
+        if err != nil {
+            return fmt.Errorf("verify %s: %w: %s", patch.Name, err, out)
+        }
+        return nil
+    }
+    out, err := exec.CommandContext(ctx, "softwareupdate", "--history").CombinedOutput()
+    if err != nil {
+        return fmt.Errorf("verify %s: %w: %s", patch.Name, err, out)
+    }
+    if !strings.Contains(string(out), patch.Name) {
+        return fmt.Errorf("%s not found in update history", patch.Name)
+    }
+    return nil
+}
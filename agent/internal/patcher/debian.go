@@ -0,0 +1,73 @@
+package patcher
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "os"
+    "os/exec"
+    "regexp"
+    "strings"
+)
+
+// DebianBackend installs patches via apt-get on Debian/Ubuntu hosts.
+type DebianBackend struct{}
+
+func (DebianBackend) Name() string { return "apt" }
+
+// aptUpgradable matches one line of "apt list --upgradable" output,
+// e.g. "bash/focal-updates 5.0-6ubuntu1.2 amd64 [upgradable from:
+// 5.0-6ubuntu1.1]".
+var aptUpgradable = regexp.MustCompile(`^(\S+)/\S+\s+(\S+)\s`)
+
+func (DebianBackend) List(ctx context.Context) ([]Patch, error) {
+    out, err := exec.CommandContext(ctx, "apt", "list", "--upgradable").CombinedOutput()
+    if err != nil {
+        return nil, fmt.Errorf("list upgradable packages: %w: %s", err, out)
+    }
+    var patches []Patch
+    for _, line := range strings.Split(string(out), "\n") {
+        m := aptUpgradable.FindStringSubmatch(line)
+        if m == nil {
+            continue
+        }
+        patches = append(patches, Patch{Name: m[1], Version: m[2]})
+    }
+    return patches, nil
+}
+
+func (DebianBackend) Install(ctx context.Context, patch Patch) (Result, error) {
+    spec := patch.Name
+    if patch.Version != "" {
+        spec = fmt.Sprintf("%s=%s", patch.Name, patch.Version)
+    }
+    cmd := exec.CommandContext(ctx, "apt-get", "-y", "install", spec)
+    var buf bytes.Buffer
+    cmd.Stdout = &buf
+    cmd.Stderr = &buf
+    err := cmd.Run()
+    result := Result{Output: buf.String(), ExitCode: exitCode(err)}
+    if err != nil {
+        return result, fmt.Errorf("apt-get install %s: %w", spec, err)
+    }
+    result.RequiresReboot = rebootRequired()
+    return result, nil
+}
+
+func (DebianBackend) Verify(ctx context.Context, patch Patch) error {
+    out, err := exec.CommandContext(ctx, "dpkg-query", "-W", "-f=${Status}", patch.Name).CombinedOutput()
+    if err != nil {
+        return fmt.Errorf("verify %s: %w: %s", patch.Name, err, out)
+    }
+    if !bytes.Contains(out, []byte("install ok installed")) {
+        return fmt.Errorf("package %s not installed after patch", patch.Name)
+    }
+    return nil
+}
+
+// rebootRequired reports whether Debian/Ubuntu has flagged a pending
+// reboot after the last package install.
+func rebootRequired() bool {
+    _, err := os.Stat("/var/run/reboot-required")
+    return err == nil
+}
@@ -0,0 +1,28 @@
+package patcher
+
+import (
+    "bufio"
+    "os"
+    "strings"
+)
+
+// linuxDistroID reads the ID field from /etc/os-release (e.g. "ubuntu",
+// "rhel", "fedora"), used to pick between the Debian and RHEL
+// package-manager backends.
+func linuxDistroID() (string, error) {
+    f, err := os.Open("/etc/os-release")
+    if err != nil {
+        return "", err
+    }
+    defer f.Close()
+
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        line := scanner.Text()
+        if !strings.HasPrefix(line, "ID=") {
+            continue
+        }
+        return strings.Trim(strings.TrimPrefix(line, "ID="), `"`), nil
+    }
+    return "", scanner.Err()
+}
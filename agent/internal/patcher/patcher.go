@@ -0,0 +1,118 @@
+// Package patcher installs OS and application patches using whatever
+// tool is native to the host: PowerShell's PSWindowsUpdate module on Windows, apt on
+// Debian/Ubuntu, dnf/yum on RHEL-family distros, and softwareupdate/brew
+// on macOS.
+package patcher
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os/exec"
+    "runtime"
+    "time"
+)
+
+// Patch describes one patch assignment fetched from the patch service.
+type Patch struct {
+    ID          int
+    Name        string
+    Version     string
+    Vendor      string
+    Severity    string
+    Description string
+    ScheduleAt  time.Time
+}
+
+// Result is what a Backend reports after attempting to install a patch.
+type Result struct {
+    Output         string
+    ExitCode       int
+    RequiresReboot bool
+}
+
+// Status values reported to the patch-status endpoint.
+const (
+    StatusInProgress     = "in_progress"
+    StatusSuccess        = "success"
+    StatusFailed         = "failed"
+    StatusRequiresReboot = "requires_reboot"
+    StatusRolledBack     = "rolled_back"
+)
+
+// Backend installs patches using the package manager/update tool native
+// to the host.
+type Backend interface {
+    Name() string
+    List(ctx context.Context) ([]Patch, error)
+    Install(ctx context.Context, patch Patch) (Result, error)
+    Verify(ctx context.Context, patch Patch) error
+}
+
+// Select picks the Backend appropriate for the current host: the native
+// tool on Windows and macOS, or the apt/dnf family on Linux as
+// determined by /etc/os-release's ID field. If dryRun is true, the
+// returned Backend runs nothing on Install and instead describes what it
+// would have installed in the Result's Output.
+func Select(dryRun bool) (Backend, error) {
+    var backend Backend
+    switch runtime.GOOS {
+    case "windows":
+        backend = &WindowsBackend{}
+    case "darwin":
+        backend = &DarwinBackend{}
+    case "linux":
+        id, err := linuxDistroID()
+        if err != nil {
+            return nil, fmt.Errorf("detect linux distro: %w", err)
+        }
+        switch id {
+        case "ubuntu", "debian":
+            backend = &DebianBackend{}
+        case "rhel", "centos", "fedora", "rocky", "almalinux":
+            backend = &RHELBackend{}
+        default:
+            return nil, fmt.Errorf("unsupported linux distro %q", id)
+        }
+    default:
+        return nil, fmt.Errorf("unsupported platform %q", runtime.GOOS)
+    }
+    if dryRun {
+        return &dryRunBackend{inner: backend}, nil
+    }
+    return backend, nil
+}
+
+// dryRunBackend wraps a Backend so List still reflects real host state,
+// Install only reports in its Result's Output what it would have run, and
+// Verify always succeeds (nothing was installed to check).
+type dryRunBackend struct {
+    inner Backend
+}
+
+func (d *dryRunBackend) Name() string { return d.inner.Name() + " (dry-run)" }
+
+func (d *dryRunBackend) List(ctx context.Context) ([]Patch, error) {
+    return d.inner.List(ctx)
+}
+
+func (d *dryRunBackend) Install(ctx context.Context, patch Patch) (Result, error) {
+    return Result{Output: fmt.Sprintf("dry-run: would install %s via %s", patch.Name, d.inner.Name())}, nil
+}
+
+func (d *dryRunBackend) Verify(ctx context.Context, patch Patch) error {
+    return nil
+}
+
+// exitCode extracts the process exit code from err, or -1 if err didn't
+// come from a *exec.ExitError (e.g. the binary itself failed to start).
+func exitCode(err error) int {
+    if err == nil {
+        return 0
+    }
+    var exitErr *exec.ExitError
+    if errors.As(err, &exitErr) {
+        return exitErr.ExitCode()
+    }
+    return -1
+}
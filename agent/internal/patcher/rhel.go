@@ -0,0 +1,78 @@
+package patcher
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "os/exec"
+    "strings"
+)
+
+// RHELBackend installs patches via dnf, falling back to yum on older
+// RHEL/CentOS releases that don't ship dnf.
+type RHELBackend struct{}
+
+func (RHELBackend) Name() string { return "dnf" }
+
+func (b RHELBackend) packageManager() string {
+    if _, err := exec.LookPath("dnf"); err == nil {
+        return "dnf"
+    }
+    return "yum"
+}
+
+func (b RHELBackend) List(ctx context.Context) ([]Patch, error) {
+    out, err := exec.CommandContext(ctx, b.packageManager(), "check-update").CombinedOutput()
+    // check-update exits 100 when updates are available; that's not a
+    // failure, just a non-empty result.
+    if err != nil && exitCode(err) != 100 {
+        return nil, fmt.Errorf("list available updates: %w: %s", err, out)
+    }
+    var patches []Patch
+    for _, line := range strings.Split(string(out), "\n") {
+        // Each update line is "name.arch  version  repo"; everything
+        // else (the metadata-refresh banner, blank lines) has the
+        // wrong shape and is skipped.
+        fields := strings.Fields(line)
+        if len(fields) != 3 {
+            continue
+        }
+        name := strings.SplitN(fields[0], ".", 2)[0]
+        patches = append(patches, Patch{Name: name, Version: fields[1]})
+    }
+    return patches, nil
+}
+
+func (b RHELBackend) Install(ctx context.Context, patch Patch) (Result, error) {
+    spec := patch.Name
+    if patch.Version != "" {
+        spec = fmt.Sprintf("%s-%s", patch.Name, patch.Version)
+    }
+    cmd := exec.CommandContext(ctx, b.packageManager(), "-y", "install", spec)
+    var buf bytes.Buffer
+    cmd.Stdout = &buf
+    cmd.Stderr = &buf
+    err := cmd.Run()
+    result := Result{Output: buf.String(), ExitCode: exitCode(err)}
+    if err != nil {
+        return result, fmt.Errorf("%s install %s: %w", b.packageManager(), spec, err)
+    }
+    result.RequiresReboot = needsRestarting(ctx)
+    return result, nil
+}
+
+// needsRestarting runs "needs-restarting -r", which exits 1 if the
+// system needs a reboot to pick up installed updates and 0 if it
+// doesn't.
+func needsRestarting(ctx context.Context) bool {
+    err := exec.CommandContext(ctx, "needs-restarting", "-r").Run()
+    return exitCode(err) == 1
+}
+
+func (b RHELBackend) Verify(ctx context.Context, patch Patch) error {
+    out, err := exec.CommandContext(ctx, "rpm", "-q", patch.Name).CombinedOutput()
+    if err != nil {
+        return fmt.Errorf("verify %s: %w: %s", patch.Name, err, out)
+    }
+    return nil
+}
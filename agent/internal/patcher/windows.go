@@ -0,0 +1,87 @@
+package patcher
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "os/exec"
+    "regexp"
+    "strings"
+)
+
+// WindowsBackend installs patches via the PSWindowsUpdate PowerShell
+// module, identifying patches by KB article ID.
+type WindowsBackend struct{}
+
+func (WindowsBackend) Name() string { return "windows" }
+
+// windowsUpdateKB matches a KB article ID anywhere in a line of
+// "Get-WindowsUpdate" output, e.g. "...KB5034122   500MB  2024-01
+// Cumulative Update...".
+var windowsUpdateKB = regexp.MustCompile(`KB\d+`)
+
+func (WindowsBackend) List(ctx context.Context) ([]Patch, error) {
+    out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", "Get-WindowsUpdate").CombinedOutput()
+    if err != nil {
+        return nil, fmt.Errorf("list windows updates: %w: %s", err, out)
+    }
+    var patches []Patch
+    for _, line := range strings.Split(string(out), "\n") {
+        kb := windowsUpdateKB.FindString(line)
+        if kb == "" {
+            continue
+        }
+        patches = append(patches, Patch{Name: kb})
+    }
+    return patches, nil
+}
+
+// kbArticleID matches a well-formed KB article ID, with or without its
+// "KB" prefix, e.g. "KB5034122" or "5034122". patch.Name comes from the
+// patch service and must be validated against this before it's
+// interpolated into a PowerShell command line; anything else is
+// rejected rather than executed.
+var kbArticleID = regexp.MustCompile(`^(?:KB)?(\d+)$`)
+
+// validateKBID checks name against kbArticleID and returns it in the
+// full "KB#####" form that Install-WindowsUpdate and Get-HotFix expect,
+// regardless of whether the "KB" prefix was present in the input.
+func validateKBID(name string) (string, error) {
+    m := kbArticleID.FindStringSubmatch(name)
+    if m == nil {
+        return "", fmt.Errorf("invalid KB article ID %q", name)
+    }
+    return "KB" + m[1], nil
+}
+
+func (WindowsBackend) Install(ctx context.Context, patch Patch) (Result, error) {
+    kb, err := validateKBID(patch.Name)
+    if err != nil {
+        return Result{}, err
+    }
+    cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command",
+        fmt.Sprintf("Install-WindowsUpdate -KBArticleID %s -AcceptAll -AutoReboot:$false", kb))
+    var buf bytes.Buffer
+    cmd.Stdout = &buf
+    cmd.Stderr = &buf
+    err = cmd.Run()
+    result := Result{Output: buf.String(), ExitCode: exitCode(err)}
+    if err != nil {
+        return result, fmt.Errorf("install %s: %w", patch.Name, err)
+    }
+    result.RequiresReboot = bytes.Contains(buf.Bytes(), []byte("RebootRequired"))
+    return result, nil
+}
+
+func (WindowsBackend) Verify(ctx context.Context, patch Patch) error {
+    kb, err := validateKBID(patch.Name)
+    if err != nil {
+        return err
+    }
+    out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command",
+        fmt.Sprintf("Get-HotFix -Id %s", kb)).CombinedOutput()
+    if err != nil {
+        return fmt.Errorf("verify %s: %w: %s", patch.Name, err, out)
+    }
+    return nil
+}
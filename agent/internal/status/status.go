@@ -0,0 +1,85 @@
+// Package status tracks the agent's own last-known state — the device
+// identity assigned at registration, when each collector last ticked,
+// and the outcome of the last patch check — so the admin endpoint can
+// report it without reaching into the running services directly.
+package status
+
+import (
+    "sync"
+    "time"
+)
+
+// PatchCheck summarizes the outcome of the agent's most recent patch
+// check.
+type PatchCheck struct {
+    Time      time.Time `json:"time"`
+    Error     string    `json:"error,omitempty"`
+    Installed int       `json:"installed"`
+}
+
+// Tracker is safe for concurrent use by any number of services and
+// readers.
+type Tracker struct {
+    mu             sync.RWMutex
+    deviceID       string
+    collectorTicks map[string]time.Time
+    lastPatchCheck PatchCheck
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+    return &Tracker{collectorTicks: make(map[string]time.Time)}
+}
+
+// SetDeviceID records the device ID assigned by the device service.
+func (t *Tracker) SetDeviceID(id string) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.deviceID = id
+}
+
+// DeviceID returns the device ID, or "" if registration hasn't
+// completed yet.
+func (t *Tracker) DeviceID() string {
+    t.mu.RLock()
+    defer t.mu.RUnlock()
+    return t.deviceID
+}
+
+// RecordCollectorTick records that collector last produced a sample at
+// at.
+func (t *Tracker) RecordCollectorTick(collector string, at time.Time) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.collectorTicks[collector] = at
+}
+
+// RecordPatchCheck records the outcome of the most recent patch check.
+func (t *Tracker) RecordPatchCheck(pc PatchCheck) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.lastPatchCheck = pc
+}
+
+// Snapshot is the JSON shape returned by the admin endpoint's /status
+// route.
+type Snapshot struct {
+    DeviceID       string               `json:"device_id,omitempty"`
+    CollectorTicks map[string]time.Time `json:"collector_ticks"`
+    LastPatchCheck PatchCheck           `json:"last_patch_check"`
+}
+
+// Snapshot returns a point-in-time copy of t's state.
+func (t *Tracker) Snapshot() Snapshot {
+    t.mu.RLock()
+    defer t.mu.RUnlock()
+    ticks := make(map[string]time.Time, len(t.collectorTicks))
+    for k, v := range t.collectorTicks {
+        ticks[k] = v
+    }
+    return Snapshot{
+        DeviceID:       t.deviceID,
+        CollectorTicks: ticks,
+        LastPatchCheck: t.lastPatchCheck,
+    }
+}
@@ -0,0 +1,104 @@
+// Package supervisor runs a fixed set of long-lived services and restarts
+// them on error with exponential backoff, in the spirit of suture v4's
+// "one supervisor, many children" model.
+package supervisor
+
+import (
+	"context"
+	"time"
+
+	"github.com/younes-barhouni/cdots/agent/internal/backoff"
+)
+
+// Service is anything the supervisor can run and restart. Serve should
+// block until ctx is cancelled or an unrecoverable error occurs, and must
+// return promptly once ctx.Done() fires.
+type Service interface {
+	Serve(ctx context.Context) error
+}
+
+// DefaultBackoff is used by Add for services that don't need a tuned
+// restart policy.
+var DefaultBackoff = backoff.Policy{Base: time.Second, Max: time.Minute, Factor: 2}
+
+type entry struct {
+	name    string
+	service Service
+	backoff backoff.Policy
+}
+
+// RestartFunc is called whenever the supervisor restarts a service after
+// an error. It lets callers log restarts without the supervisor itself
+// depending on a logging package.
+type RestartFunc func(name string, err error, attempt int, delay time.Duration)
+
+// Supervisor restarts its registered services independently: each one
+// runs in its own goroutine, and a failure in one never affects the
+// others.
+type Supervisor struct {
+	entries []entry
+	onRestart RestartFunc
+}
+
+// New returns an empty Supervisor ready to have services added to it.
+func New() *Supervisor {
+	return &Supervisor{}
+}
+
+// Add registers svc under name with DefaultBackoff.
+func (s *Supervisor) Add(name string, svc Service) {
+	s.AddWithBackoff(name, svc, DefaultBackoff)
+}
+
+// AddWithBackoff registers svc under name with a service-specific restart
+// policy.
+func (s *Supervisor) AddWithBackoff(name string, svc Service, policy backoff.Policy) {
+	s.entries = append(s.entries, entry{name: name, service: svc, backoff: policy})
+}
+
+// OnRestart installs fn to be called whenever a service is restarted.
+func (s *Supervisor) OnRestart(fn RestartFunc) {
+	s.onRestart = fn
+}
+
+// Serve runs every registered service until ctx is cancelled, propagating
+// the cancellation into each one. It blocks until all services have
+// exited.
+func (s *Supervisor) Serve(ctx context.Context) error {
+	done := make(chan struct{}, len(s.entries))
+	for _, e := range s.entries {
+		e := e
+		go func() {
+			s.run(ctx, e)
+			done <- struct{}{}
+		}()
+	}
+	for range s.entries {
+		<-done
+	}
+	return nil
+}
+
+// run drives a single service, restarting it with backoff until ctx is
+// cancelled or the service returns a nil error (a clean, intentional
+// stop).
+func (s *Supervisor) run(ctx context.Context, e entry) {
+	for attempt := 0; ; attempt++ {
+		err := e.service.Serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			return
+		}
+		delay := e.backoff.Delay(attempt)
+		if s.onRestart != nil {
+			s.onRestart(e.name, err, attempt, delay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}